@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// backupSuffix is appended to a dict file's path to get its pristine,
+// pre-iceminus copy.
+const backupSuffix = ".iceminus.bak"
+
+func backupPath(path string) string {
+	return path + backupSuffix
+}
+
+// ensureBackup writes the current on-disk contents of path to its backup
+// file, but only if no backup exists yet, so repeated runs never clobber the
+// true original with an already-commented copy.
+func ensureBackup(path string) error {
+	bp := backupPath(path)
+	if _, err := os.Stat(bp); err == nil {
+		return nil
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(bp, data, info.Mode().Perm())
+}
+
+// writeFileAtomic writes data to a temp file alongside path and renames it
+// into place, falling back to remove-then-rename and finally an in-place
+// overwrite when the destination is locked or read-only (as can happen on
+// Windows). Shared by the forward edit path in processFile and the restore
+// path in runRestore so both get the same crash-safety guarantees.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp := path + ".tmp_iceminus"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	// Try atomic rename first.
+	if err := os.Rename(tmp, path); err == nil {
+		return nil
+	}
+	// On Windows, rename may fail if the destination is locked or read-only.
+	// Try to remove destination then rename.
+	renameErr := error(nil)
+	if remErr := os.Remove(path); remErr == nil {
+		if err := os.Rename(tmp, path); err == nil {
+			return nil
+		} else {
+			renameErr = err
+		}
+	} else {
+		// attempt to make file writable and remove again
+		_ = os.Chmod(path, 0644)
+		if remErr2 := os.Remove(path); remErr2 == nil {
+			if err := os.Rename(tmp, path); err == nil {
+				return nil
+			} else {
+				renameErr = err
+			}
+		}
+	}
+	// Fallback: overwrite the original file contents.
+	tmpData, readErr := os.ReadFile(tmp)
+	if readErr != nil {
+		return fmt.Errorf("rename failed: %v; read tmp failed: %v", renameErr, readErr)
+	}
+	of, openErr := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if openErr != nil {
+		// try to change permissions then open again
+		_ = os.Chmod(path, 0644)
+		of, openErr = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+		if openErr != nil {
+			return fmt.Errorf("failed to overwrite file after rename failure: %v; open error: %v", renameErr, openErr)
+		}
+	}
+	if _, writeErr := of.Write(tmpData); writeErr != nil {
+		_ = of.Close()
+		return writeErr
+	}
+	_ = of.Close()
+	_ = os.Remove(tmp)
+	return nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const cacheFileName = "cache.jsonl"
+
+// compactThreshold is the number of lines the cache file is allowed to grow
+// to (across repeated appends for the same path) before it's rewritten down
+// to one entry per path on the next startup.
+const compactThreshold = 5000
+
+// cacheResult records why a file was safe to skip on a later run.
+type cacheResult string
+
+const (
+	cacheResultClean    cacheResult = "no_matches"
+	cacheResultComplete cacheResult = "already_commented"
+)
+
+// cacheEntry is one line of the on-disk cache. FileHash, WordsHash and
+// OptsHash together pin the entry to the exact file contents, sensitive-word
+// list, and scan options (--yaml-aware, --match-columns, --legacy-lines)
+// that produced Result, so changing any of them invalidates it.
+type cacheEntry struct {
+	Path      string      `json:"path"`
+	FileHash  string      `json:"file_hash"`
+	WordsHash string      `json:"words_hash"`
+	OptsHash  string      `json:"opts_hash"`
+	Result    cacheResult `json:"result"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// fileCache is an append-only JSON-lines cache keyed by absolute path,
+// letting processPath skip files that haven't changed since the last run.
+// lookup/record are called concurrently by the worker pool, so mu guards
+// both the in-memory map and the on-disk append.
+type fileCache struct {
+	dir     string
+	mu      sync.Mutex
+	f       *os.File
+	entries map[string]cacheEntry
+	lines   int
+}
+
+// defaultCacheDir returns $XDG_CACHE_HOME/iceminus, falling back to
+// ~/.cache/iceminus, or %LocalAppData%\iceminus\cache on Windows.
+func defaultCacheDir() string {
+	if runtime.GOOS == "windows" {
+		if lad := os.Getenv("LocalAppData"); lad != "" {
+			return filepath.Join(lad, "iceminus", "cache")
+		}
+	}
+	if xdg := os.Getenv("XDG_CACHE_HOME"); xdg != "" {
+		return filepath.Join(xdg, "iceminus")
+	}
+	if home, err := os.UserHomeDir(); err == nil && home != "" {
+		return filepath.Join(home, ".cache", "iceminus")
+	}
+	return ".iceminus-cache"
+}
+
+func cacheFilePath(dir string) string {
+	return filepath.Join(dir, cacheFileName)
+}
+
+// openCache loads the existing cache (compacting it first if it's grown
+// past compactThreshold lines) and opens it for append.
+func openCache(dir string) (*fileCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	path := cacheFilePath(dir)
+	entries, lines, err := loadCacheEntries(path)
+	if err != nil {
+		return nil, err
+	}
+	if lines > compactThreshold && lines > len(entries) {
+		if err := writeCacheEntries(path, entries); err != nil {
+			return nil, err
+		}
+		lines = len(entries)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &fileCache{dir: dir, f: f, entries: entries, lines: lines}, nil
+}
+
+func loadCacheEntries(path string) (map[string]cacheEntry, int, error) {
+	entries := make(map[string]cacheEntry)
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries, 0, nil
+		}
+		return nil, 0, err
+	}
+	defer func() { _ = f.Close() }()
+
+	lines := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		lines++
+		var entry cacheEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			continue // tolerate a truncated last line from a crashed run
+		}
+		entries[entry.Path] = entry
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+	return entries, lines, nil
+}
+
+func writeCacheEntries(path string, entries map[string]cacheEntry) error {
+	var sb strings.Builder
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		sb.Write(data)
+		sb.WriteByte('\n')
+	}
+	return writeFileAtomic(path, []byte(sb.String()), 0644)
+}
+
+// lookup reports whether path was already scanned with the given file,
+// word-list, and scan-options hashes, and its cached result was safe to
+// skip.
+func (c *fileCache) lookup(path, fileHash, wordsHash, optsHash string) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[path]
+	if !ok || entry.FileHash != fileHash || entry.WordsHash != wordsHash || entry.OptsHash != optsHash {
+		return cacheEntry{}, false
+	}
+	if entry.Result != cacheResultClean && entry.Result != cacheResultComplete {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+// record appends (or updates in memory) the outcome of scanning path.
+func (c *fileCache) record(path, fileHash, wordsHash, optsHash string, result cacheResult) error {
+	entry := cacheEntry{
+		Path:      path,
+		FileHash:  fileHash,
+		WordsHash: wordsHash,
+		OptsHash:  optsHash,
+		Result:    result,
+		Timestamp: time.Now().UTC(),
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[path] = entry
+	if _, err := c.f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	c.lines++
+	return nil
+}
+
+func (c *fileCache) Close() error {
+	return c.f.Close()
+}
+
+// clearCache removes the on-disk cache entirely, used by --cache-clear.
+func clearCache(dir string) error {
+	err := os.Remove(cacheFilePath(dir))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashWords hashes a sorted, normalized copy of the sensitive-word list so
+// the hash is stable regardless of the source file's line order, and so
+// adding or removing a word reliably invalidates every cached entry.
+func hashWords(words []string) string {
+	normalized := make([]string, len(words))
+	copy(normalized, words)
+	sort.Strings(normalized)
+	h := sha256.New()
+	for _, w := range normalized {
+		fmt.Fprintln(h, w)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashOpts hashes the scanOptions fields that change what counts as a match
+// (--yaml-aware, --yaml-aware=strict, --match-columns), so switching between
+// matching modes invalidates rather than silently reusing a stale cache
+// entry from a different mode.
+func hashOpts(opts scanOptions) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "yaml-aware=%v\nstrict=%v\n", opts.YAMLAware, opts.Strict)
+	columns := make([]string, len(opts.MatchColumns))
+	copy(columns, opts.MatchColumns)
+	sort.Strings(columns)
+	for _, c := range columns {
+		fmt.Fprintln(h, c)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
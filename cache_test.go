@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestProcessOneCachesByAbsolutePath verifies that a file scanned via a
+// relative path is recorded in the cache under its absolute path, so a later
+// run from a different working directory (or with a differently-spelled but
+// equivalent path) still hits the cache instead of silently re-scanning.
+func TestProcessOneCachesByAbsolutePath(t *testing.T) {
+	dir := t.TempDir()
+	const name = "dict.yaml"
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("entry\tcode\t1\n"), 0644); err != nil {
+		t.Fatalf("write fixture file: %v", err)
+	}
+
+	cacheDir := t.TempDir()
+	cache, err := openCache(cacheDir)
+	if err != nil {
+		t.Fatalf("openCache: %v", err)
+	}
+	defer func() { _ = cache.Close() }()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Chdir: %v", err)
+	}
+	defer func() { _ = os.Chdir(wd) }()
+
+	matcher := newAhoCorasick([]string{"badword"})
+	sc := &scanContext{
+		Matcher: matcher,
+		Cache:   cache,
+		Opts:    scanOptions{YAMLAware: false},
+		Printer: newPrinter(),
+	}
+	defer sc.Printer.Close()
+
+	stats := &procStats{OpsPerFile: make(map[string]int)}
+	if err := processOne(name, sc, stats); err != nil {
+		t.Fatalf("processOne(relative path): %v", err)
+	}
+
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		t.Fatalf("filepath.Abs: %v", err)
+	}
+	fileHash, err := hashFile(abs)
+	if err != nil {
+		t.Fatalf("hashFile: %v", err)
+	}
+	if _, ok := cache.lookup(abs, fileHash, sc.WordsHash, sc.OptsHash); !ok {
+		t.Fatalf("cache entry not recorded under absolute path %s", abs)
+	}
+
+	stats2 := &procStats{OpsPerFile: make(map[string]int)}
+	if err := processOne("./"+name, sc, stats2); err != nil {
+		t.Fatalf("processOne(differently-spelled relative path): %v", err)
+	}
+	if stats2.FilesCached != 1 {
+		t.Fatalf("FilesCached = %d, want 1 (second scan should hit the cache)", stats2.FilesCached)
+	}
+}
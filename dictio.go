@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/uselibrary/iceminus/internal/compress"
+)
+
+// decompressedReader detects gzip/xz on the leading bytes of an already
+// opened file and returns a reader over its decompressed content, along
+// with the codec found (so the caller can re-compress on write) and, for
+// gzip, the compression level probed from the stream's XFL header byte (0 if
+// unknown). Uncompressed files are streamed straight from disk; compressed
+// files are fully decompressed into memory, since Rime dicts are small enough
+// for that to be cheap next to the network/disk cost of syncing them in the
+// first place.
+func decompressedReader(f *os.File) (io.Reader, compress.Codec, int, error) {
+	peek := make([]byte, 9)
+	n, err := io.ReadFull(f, peek)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, compress.None, 0, err
+	}
+	peek = peek[:n]
+	codec := compress.DetectCompression(peek)
+	level := compress.ProbeGzipLevel(peek)
+
+	var r io.Reader = io.MultiReader(bytes.NewReader(peek), f)
+	if codec == compress.None {
+		return r, codec, level, nil
+	}
+	zr, err := compress.NewReader(r, codec)
+	if err != nil {
+		return nil, codec, level, err
+	}
+	decompressed, err := io.ReadAll(zr)
+	_ = zr.Close()
+	if err != nil {
+		return nil, codec, level, err
+	}
+	return bytes.NewReader(decompressed), codec, level, nil
+}
+
+// writeCommented backs up path (if not already backed up) and writes
+// outLines back to it, re-compressing with codec if the original file was
+// compressed. level re-applies the compression level decompressedReader
+// probed from the original stream; 0 falls back to gzip.BestCompression.
+func writeCommented(path string, codec compress.Codec, level int, outLines []string) error {
+	if err := ensureBackup(path); err != nil {
+		return err
+	}
+	data := []byte(strings.Join(outLines, ""))
+	if codec != compress.None {
+		var buf bytes.Buffer
+		zw, err := compress.NewWriter(&buf, codec, level)
+		if err != nil {
+			return err
+		}
+		if _, err := zw.Write(data); err != nil {
+			_ = zw.Close()
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	}
+	return writeFileAtomic(path, data, 0644)
+}
@@ -0,0 +1,113 @@
+// Package compress detects and transparently wraps the compression
+// codecs iceminus needs to read and write in place: gzip and xz. Detection
+// is by magic number rather than file extension, so a caller that has
+// already opened a file (e.g. to peek at its header) doesn't need to know
+// the path.
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/ulikunitz/xz"
+)
+
+// Codec identifies a compression format iceminus knows how to read/write.
+type Codec int
+
+const (
+	// None means the data is not compressed.
+	None Codec = iota
+	Gzip
+	XZ
+)
+
+var (
+	gzipMagic = []byte{0x1F, 0x8B, 0x08}
+	xzMagic   = []byte{0xFD, 0x37, 0x7A, 0x58, 0x5A, 0x00}
+)
+
+// DetectCompression inspects the leading bytes of a file (a short peek is
+// enough; magic numbers are at most 6 bytes) and returns the codec it
+// starts with, or None if it matches neither known magic number.
+func DetectCompression(peek []byte) Codec {
+	if bytes.HasPrefix(peek, xzMagic) {
+		return XZ
+	}
+	if bytes.HasPrefix(peek, gzipMagic) {
+		return Gzip
+	}
+	return None
+}
+
+// gzipHeaderLen is the number of leading bytes needed to reach a gzip
+// stream's XFL byte (ID1 ID2 CM FLG MTIME(4) XFL), which hints at the
+// compression level the stream was written with.
+const gzipHeaderLen = 9
+
+// ProbeGzipLevel inspects a gzip stream's XFL header byte and returns the
+// gzip.Best*-style level it was likely written with, or 0 if peek is too
+// short or the byte doesn't match a level ProbeGzipLevel recognizes.
+// RFC 1952 defines XFL 2 for "compressor used maximum compression" and XFL 4
+// for "compressor used fastest algorithm"; anything else is left to the
+// caller to default.
+func ProbeGzipLevel(peek []byte) int {
+	if len(peek) < gzipHeaderLen || !bytes.HasPrefix(peek, gzipMagic) {
+		return 0
+	}
+	switch peek[gzipHeaderLen-1] {
+	case 2:
+		return gzip.BestCompression
+	case 4:
+		return gzip.BestSpeed
+	default:
+		return 0
+	}
+}
+
+// NewReader wraps r in a decompressing reader for codec. For codec == None
+// it returns r unchanged, wrapped to satisfy io.ReadCloser.
+func NewReader(r io.Reader, codec Codec) (io.ReadCloser, error) {
+	switch codec {
+	case None:
+		return io.NopCloser(r), nil
+	case Gzip:
+		return gzip.NewReader(r)
+	case XZ:
+		zr, err := xz.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(zr), nil
+	default:
+		return nil, fmt.Errorf("compress: unknown codec %d", codec)
+	}
+}
+
+// NewWriter wraps w in a compressing writer for codec. level is a
+// gzip.Best*-style compression level and is ignored for codecs (xz, none)
+// that don't expose one. Callers must Close the returned writer to flush
+// trailing compressed data.
+func NewWriter(w io.Writer, codec Codec, level int) (io.WriteCloser, error) {
+	switch codec {
+	case None:
+		return nopWriteCloser{w}, nil
+	case Gzip:
+		if level == 0 {
+			level = gzip.BestCompression
+		}
+		return gzip.NewWriterLevel(w, level)
+	case XZ:
+		return xz.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("compress: unknown codec %d", codec)
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
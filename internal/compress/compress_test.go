@@ -0,0 +1,87 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestDetectCompression(t *testing.T) {
+	cases := []struct {
+		name string
+		peek []byte
+		want Codec
+	}{
+		{"gzip", []byte{0x1F, 0x8B, 0x08, 0x00}, Gzip},
+		{"xz", []byte{0xFD, 0x37, 0x7A, 0x58, 0x5A, 0x00, 0x00}, XZ},
+		{"plain", []byte("name: foo\n"), None},
+		{"short", []byte{0x1F}, None},
+	}
+	for _, c := range cases {
+		if got := DetectCompression(c.peek); got != c.want {
+			t.Errorf("%s: DetectCompression() = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestProbeGzipLevel(t *testing.T) {
+	levelOf := func(level int) int {
+		var buf bytes.Buffer
+		w, err := NewWriter(&buf, Gzip, level)
+		if err != nil {
+			t.Fatalf("NewWriter(level=%d): %v", level, err)
+		}
+		if _, err := io.WriteString(w, "text\tcode\t100\n"); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("close: %v", err)
+		}
+		peek := buf.Bytes()
+		if len(peek) > gzipHeaderLen {
+			peek = peek[:gzipHeaderLen]
+		}
+		return ProbeGzipLevel(peek)
+	}
+
+	if got := levelOf(9); got != 9 {
+		t.Errorf("ProbeGzipLevel after level 9 write = %d, want %d (gzip.BestCompression)", got, 9)
+	}
+	if got := levelOf(1); got != 1 {
+		t.Errorf("ProbeGzipLevel after level 1 write = %d, want %d (gzip.BestSpeed)", got, 1)
+	}
+	if got := ProbeGzipLevel([]byte{0x1F}); got != 0 {
+		t.Errorf("ProbeGzipLevel(short peek) = %d, want 0", got)
+	}
+	if got := ProbeGzipLevel([]byte("name: foo\n")); got != 0 {
+		t.Errorf("ProbeGzipLevel(non-gzip) = %d, want 0", got)
+	}
+}
+
+func TestGzipRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Gzip, 0)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	want := "text\tcode\t100\n"
+	if _, err := io.WriteString(w, want); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	r, err := NewReader(&buf, Gzip)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	defer func() { _ = r.Close() }()
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("round trip = %q, want %q", got, want)
+	}
+}
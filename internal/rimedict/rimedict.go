@@ -0,0 +1,157 @@
+// Package rimedict understands the shape of a Rime dictionary file: a YAML
+// front-matter block terminated by "..." or a second "---", followed by a
+// tab-separated body of text/code/weight entries. It lets callers tell
+// header lines apart from body entries instead of treating every line the
+// same way.
+package rimedict
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Entry is one parsed body line: text<TAB>code<TAB>weight.
+type Entry struct {
+	Text   string
+	Code   string
+	Weight int
+	Raw    string
+	LineNo int
+}
+
+// LineKind classifies a line the Scanner produced.
+type LineKind int
+
+const (
+	KindHeader LineKind = iota
+	KindBody
+	KindComment
+	KindBlank
+)
+
+// Line is one line of a dict file together with its classification. Entry
+// is only populated when Kind is KindBody.
+type Line struct {
+	Kind   LineKind
+	Raw    string
+	LineNo int
+	Entry  Entry
+}
+
+type headerState int
+
+const (
+	beforeHeader headerState = iota
+	inHeader
+	afterHeader
+)
+
+// Scanner reads a Rime dict file line by line, tracking whether the reader
+// is still inside the YAML front matter or has reached the TSV body.
+type Scanner struct {
+	r      *bufio.Reader
+	state  headerState
+	lineNo int
+	line   Line
+	err    error
+}
+
+func NewScanner(r io.Reader) *Scanner {
+	return &Scanner{r: bufio.NewReader(r)}
+}
+
+// Scan advances to the next line, returning false at EOF or on error; check
+// Err after a false return to tell the two apart.
+func (s *Scanner) Scan() bool {
+	if s.err != nil {
+		return false
+	}
+	raw, err := s.r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		s.err = err
+		return false
+	}
+	if err == io.EOF && raw == "" {
+		return false
+	}
+	s.lineNo++
+
+	content := raw
+	if strings.HasSuffix(content, "\n") {
+		content = content[:len(content)-1]
+	}
+	trimmed := strings.TrimSpace(content)
+
+	line := Line{Raw: raw, LineNo: s.lineNo}
+
+	switch s.state {
+	case beforeHeader:
+		switch {
+		case trimmed == "---":
+			s.state = inHeader
+			line.Kind = KindHeader
+		case trimmed == "":
+			line.Kind = KindBlank
+		case strings.HasPrefix(trimmed, "#"):
+			// Comment preamble (e.g. "# Rime dictionary") before the "---"
+			// marker; stay in beforeHeader so the real marker is still
+			// recognized once it arrives.
+			line.Kind = KindComment
+		default:
+			// No front matter present (legacy or malformed dict); treat the
+			// whole file as body from here on.
+			s.state = afterHeader
+			line.Kind = classify(content, &line)
+		}
+	case inHeader:
+		line.Kind = KindHeader
+		if trimmed == "..." || trimmed == "---" {
+			s.state = afterHeader
+		}
+	case afterHeader:
+		switch {
+		case trimmed == "":
+			line.Kind = KindBlank
+		default:
+			line.Kind = classify(content, &line)
+		}
+	}
+
+	s.line = line
+	return true
+}
+
+// classify fills in line.Entry for a body line and returns its Kind, or
+// reports KindComment for a line iceminus has already commented out.
+func classify(content string, line *Line) LineKind {
+	if strings.HasPrefix(content, "#") {
+		return KindComment
+	}
+	fields := strings.Split(content, "\t")
+	entry := Entry{Raw: line.Raw, LineNo: line.LineNo}
+	if len(fields) > 0 {
+		entry.Text = fields[0]
+	}
+	if len(fields) > 1 {
+		entry.Code = fields[1]
+	}
+	if len(fields) > 2 {
+		if w, err := strconv.Atoi(strings.TrimSpace(fields[2])); err == nil {
+			entry.Weight = w
+		}
+	}
+	line.Entry = entry
+	return KindBody
+}
+
+// Line returns the most recently scanned line.
+func (s *Scanner) Line() Line {
+	return s.line
+}
+
+// Err returns the first non-EOF error encountered by Scan.
+func (s *Scanner) Err() error {
+	return s.err
+}
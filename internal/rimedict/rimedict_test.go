@@ -0,0 +1,92 @@
+package rimedict
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScannerClassifiesHeaderAndBody(t *testing.T) {
+	src := "---\nname: test_dict\nversion: \"1.0\"\n...\nhello\tni hao\t100\n# already commented\tcode\t1\n\nworld\tshi jie\t50\n"
+	sc := NewScanner(strings.NewReader(src))
+
+	var kinds []LineKind
+	var entries []Entry
+	for sc.Scan() {
+		line := sc.Line()
+		kinds = append(kinds, line.Kind)
+		if line.Kind == KindBody {
+			entries = append(entries, line.Entry)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+
+	want := []LineKind{KindHeader, KindHeader, KindHeader, KindHeader, KindBody, KindComment, KindBlank, KindBody}
+	if len(kinds) != len(want) {
+		t.Fatalf("got %d lines, want %d: %v", len(kinds), len(want), kinds)
+	}
+	for i, k := range want {
+		if kinds[i] != k {
+			t.Errorf("line %d: kind = %v, want %v", i+1, kinds[i], k)
+		}
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("got %d body entries, want 2", len(entries))
+	}
+	if entries[0].Text != "hello" || entries[0].Code != "ni hao" || entries[0].Weight != 100 {
+		t.Errorf("entries[0] = %+v", entries[0])
+	}
+	if entries[1].Text != "world" || entries[1].Code != "shi jie" || entries[1].Weight != 50 {
+		t.Errorf("entries[1] = %+v", entries[1])
+	}
+}
+
+func TestScannerCommentPreambleBeforeHeader(t *testing.T) {
+	src := "# Rime dictionary\n# encoding: utf-8\n---\nname: test_dict\nversion: \"1.0\"\n...\nhello\tni hao\t100\n"
+	sc := NewScanner(strings.NewReader(src))
+
+	var kinds []LineKind
+	var entries []Entry
+	for sc.Scan() {
+		line := sc.Line()
+		kinds = append(kinds, line.Kind)
+		if line.Kind == KindBody {
+			entries = append(entries, line.Entry)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+
+	want := []LineKind{KindComment, KindComment, KindHeader, KindHeader, KindHeader, KindHeader, KindBody}
+	if len(kinds) != len(want) {
+		t.Fatalf("got %d lines, want %d: %v", len(kinds), len(want), kinds)
+	}
+	for i, k := range want {
+		if kinds[i] != k {
+			t.Errorf("line %d: kind = %v, want %v", i+1, kinds[i], k)
+		}
+	}
+
+	if len(entries) != 1 || entries[0].Text != "hello" {
+		t.Errorf("entries = %+v", entries)
+	}
+}
+
+func TestScannerLegacyFileWithoutFrontMatter(t *testing.T) {
+	src := "hello\tni hao\t100\nworld\tshi jie\t50\n"
+	sc := NewScanner(strings.NewReader(src))
+
+	var count int
+	for sc.Scan() {
+		if sc.Line().Kind != KindBody {
+			t.Errorf("line %d: kind = %v, want KindBody", count+1, sc.Line().Kind)
+		}
+		count++
+	}
+	if count != 2 {
+		t.Errorf("got %d lines, want 2", count)
+	}
+}
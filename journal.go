@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// journalName is the sidecar file iceminus appends one JSON line to per
+// comment operation, so a run can be undone later.
+const journalName = ".iceminus-journal.jsonl"
+
+func journalPath(folderPath string) string {
+	return filepath.Join(folderPath, journalName)
+}
+
+// JournalEntry records a single "# " comment operation so it can be
+// replayed in reverse by --undo or --restore.
+type JournalEntry struct {
+	File         string    `json:"file"`
+	Line         int       `json:"line"`
+	OriginalText string    `json:"original_text"`
+	WordsMatched []string  `json:"words_matched"`
+	Timestamp    time.Time `json:"timestamp"`
+	RunID        string    `json:"run_id"`
+}
+
+// runRecorder appends journal entries for a single invocation of iceminus.
+// It is shared read-write state across every processFile call in a run,
+// including concurrent calls from the worker pool in processPath, so the
+// journal file is opened once, guarded by mu, and fsync'd after each append.
+type runRecorder struct {
+	runID string
+	mu    sync.Mutex
+	f     *os.File
+}
+
+// newRunID derives a run identifier from the current time, which is unique
+// enough for a tool that processes one dict folder at a time and lets
+// --undo reports stay human-readable.
+func newRunID() string {
+	return time.Now().UTC().Format("20060102T150405.000000000Z")
+}
+
+func newRunRecorder(folderPath, runID string) (*runRecorder, error) {
+	f, err := os.OpenFile(journalPath(folderPath), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &runRecorder{runID: runID, f: f}, nil
+}
+
+func (r *runRecorder) record(file string, line int, originalText string, wordsMatched []string) error {
+	entry := JournalEntry{
+		File:         file,
+		Line:         line,
+		OriginalText: originalText,
+		WordsMatched: wordsMatched,
+		Timestamp:    time.Now().UTC(),
+		RunID:        r.runID,
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return r.f.Sync()
+}
+
+func (r *runRecorder) Close() error {
+	return r.f.Close()
+}
+
+// loadJournal reads every entry from the sidecar journal. A missing journal
+// is not an error; it just means nothing has been recorded yet.
+func loadJournal(path string) ([]JournalEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, fmt.Errorf("parse journal line: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// saveJournal rewrites the sidecar journal from scratch, used after an undo
+// has consumed some of its entries.
+func saveJournal(path string, entries []JournalEntry) error {
+	var sb strings.Builder
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		sb.Write(data)
+		sb.WriteByte('\n')
+	}
+	return writeFileAtomic(path, []byte(sb.String()), 0644)
+}
@@ -2,7 +2,7 @@ package main
 
 import (
 	"bufio"
-	_ "embed"
+	"bytes"
 	"flag"
 	"fmt"
 	"io"
@@ -10,10 +10,14 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+
+	"github.com/uselibrary/iceminus/internal/compress"
 )
 
-//go:embed sensitive_words.txt
-var embeddedSensitive string
+// defaultSensitivePath is the --sensitive value callers get unless they
+// override it. There's no list built into the binary; if it doesn't exist on
+// disk, loadSensitive starts from an empty list instead of failing.
+const defaultSensitivePath = "sensitive_words.txt"
 
 func main() {
 	// determine default path for --path on Windows
@@ -25,9 +29,44 @@ func main() {
 	}
 	path := flag.String("path", defaultPath, "directory or file path to scan for yaml files")
 	dry := flag.Bool("dry-run", false, "print what would be changed without modifying files")
-	sensPath := flag.String("sensitive", "sensitive_words.txt", "path to sensitive words file")
+	sensPath := flag.String("sensitive", defaultSensitivePath, "path to sensitive words file")
+	matcherName := flag.String("matcher", "ahocorasick", "word-matching strategy: ahocorasick or naive")
+	undo := flag.Bool("undo", false, "undo the comment operations from a run (most recent run unless --run-id is given)")
+	undoRunID := flag.String("run-id", "", "run id to undo; only used with --undo, defaults to the most recent run")
+	restoreFile := flag.String("restore", "", "restore a single file to its pre-iceminus backup")
+	cacheDir := flag.String("cache", defaultCacheDir(), "directory holding the incremental scan cache")
+	noCache := flag.Bool("no-cache", false, "disable the incremental scan cache for this run")
+	cacheClear := flag.Bool("cache-clear", false, "delete the incremental scan cache and exit")
+	yamlAwareFlag := flag.String("yaml-aware", "on", "structured Rime-dict editing: on, off, or strict")
+	legacyLines := flag.Bool("legacy-lines", false, "match whole raw lines instead of the YAML/TSV-aware scan")
+	matchColumnsFlag := flag.String("match-columns", "text", "comma-separated TSV columns to match against: text, code")
+	jobs := flag.Int("jobs", runtime.NumCPU(), "number of files to scan concurrently")
 	flag.Parse()
 
+	if *cacheClear {
+		if err := clearCache(*cacheDir); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *restoreFile != "" {
+		if err := runRestore(*restoreFile); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *undo {
+		if err := runUndo(resolveFolderPath(*path), *undoRunID); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if *path == "" {
 		fmt.Fprintln(os.Stderr, "usage: iceminus --path <path> [--dry-run] [--sensitive <file>]")
 		os.Exit(2)
@@ -43,17 +82,72 @@ func main() {
 		os.Exit(0)
 	}
 
-	// determine folder path (absolute). If a file was provided, use its parent dir.
-	absPath, _ := filepath.Abs(*path)
-	folderPath := absPath
-	if info, statErr := os.Stat(*path); statErr == nil {
-		if !info.IsDir() {
-			folderPath = filepath.Dir(absPath)
+	var matcher Matcher
+	switch *matcherName {
+	case "naive":
+		matcher = newNaiveMatcher(words)
+	case "ahocorasick", "":
+		matcher = newAhoCorasick(words)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown --matcher %q; want ahocorasick or naive\n", *matcherName)
+		os.Exit(2)
+	}
+
+	yamlAware, strict, err := parseYAMLAwareFlag(*yamlAwareFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+	if *legacyLines {
+		yamlAware = false
+	}
+	matchColumns, err := parseMatchColumns(*matchColumnsFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+	opts := scanOptions{YAMLAware: yamlAware, Strict: strict, MatchColumns: matchColumns}
+
+	folderPath := resolveFolderPath(*path)
+
+	var rec *runRecorder
+	if !*dry {
+		rec, err = newRunRecorder(folderPath, newRunID())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to open journal: %v\n", err)
+			os.Exit(1)
 		}
+		defer func() { _ = rec.Close() }()
+	}
+
+	var cache *fileCache
+	var wordsHash, optsHash string
+	if !*noCache {
+		cache, err = openCache(*cacheDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to open cache: %v\n", err)
+			os.Exit(1)
+		}
+		defer func() { _ = cache.Close() }()
+		wordsHash = hashWords(words)
+		optsHash = hashOpts(opts)
+	}
+
+	p := newPrinter()
+	sc := &scanContext{
+		Matcher:   matcher,
+		DryRun:    *dry,
+		Rec:       rec,
+		Cache:     cache,
+		WordsHash: wordsHash,
+		OptsHash:  optsHash,
+		Opts:      opts,
+		Printer:   p,
 	}
 
 	stats := &procStats{OpsPerFile: make(map[string]int)}
-	err = processPath(*path, words, *dry, stats)
+	err = processPath(*path, sc, stats, *jobs)
+	p.Close()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
@@ -63,6 +157,7 @@ func main() {
 	fmt.Printf("\nSummary:\n")
 	fmt.Printf("  scanned folder: %s\n", folderPath)
 	fmt.Printf("  yaml files scanned: %d\n", stats.FilesScanned)
+	fmt.Printf("  files cached (skipped): %d\n", stats.FilesCached)
 	fmt.Printf("  files with matches: %d\n", stats.FilesWithMatches)
 	fmt.Printf("  total matched lines: %d\n", stats.TotalMatches)
 	if len(stats.OpsPerFile) > 0 {
@@ -73,27 +168,64 @@ func main() {
 	}
 }
 
+// resolveFolderPath returns the absolute directory a run operates on: path
+// itself if it's a directory, or its parent if it's a single file. This is
+// also where the run's journal (.iceminus-journal.jsonl) lives, so --undo
+// needs to derive the same folder from the same --path flag.
+func resolveFolderPath(path string) string {
+	absPath, _ := filepath.Abs(path)
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		return filepath.Dir(absPath)
+	}
+	return absPath
+}
+
+// isDictFile reports whether path looks like a Rime dict the walker should
+// scan: a .yaml/.yml file, optionally gzip- or xz-compressed.
+func isDictFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".gz" || ext == ".xz" {
+		ext = strings.ToLower(filepath.Ext(strings.TrimSuffix(path, ext)))
+	}
+	return ext == ".yaml" || ext == ".yml"
+}
+
 type procStats struct {
 	FilesScanned     int
+	FilesCached      int
 	FilesWithMatches int
 	TotalMatches     int
 	OpsPerFile       map[string]int
 }
 
 func loadSensitive(path string) ([]string, error) {
-	var src string
-	// use embedded content by default when the provided path equals the default
-	if path == "" || path == "sensitive_words.txt" {
-		src = embeddedSensitive
-	} else {
-		b, err := os.ReadFile(path)
+	if path == "" {
+		path = defaultSensitivePath
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && path == defaultSensitivePath {
+			// No word list shipped next to the binary or supplied via
+			// --sensitive; start from an empty list rather than requiring
+			// one to exist.
+			return nil, nil
+		}
+		return nil, err
+	}
+	codec := compress.DetectCompression(b)
+	if codec != compress.None {
+		zr, err := compress.NewReader(bytes.NewReader(b), codec)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = zr.Close() }()
+		b, err = io.ReadAll(zr)
 		if err != nil {
 			return nil, err
 		}
-		src = string(b)
 	}
 	var words []string
-	scanner := bufio.NewScanner(strings.NewReader(src))
+	scanner := bufio.NewScanner(strings.NewReader(string(b)))
 	for scanner.Scan() {
 		t := strings.TrimSpace(scanner.Text())
 		if t == "" {
@@ -107,65 +239,39 @@ func loadSensitive(path string) ([]string, error) {
 	return words, nil
 }
 
-func processPath(root string, words []string, dryRun bool, stats *procStats) error {
-	info, err := os.Stat(root)
-	if err != nil {
-		return err
-	}
-	if info.IsDir() {
-		return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
-			if err != nil {
-				return err
-			}
-			if d.IsDir() {
-				return nil
-			}
-			ext := strings.ToLower(filepath.Ext(path))
-			if ext == ".yaml" || ext == ".yml" {
-				stats.FilesScanned++
-				cnt, err := processFile(path, words, dryRun)
-				if err != nil {
-					return err
-				}
-				if cnt > 0 {
-					stats.FilesWithMatches++
-					stats.TotalMatches += cnt
-					stats.OpsPerFile[path] = cnt
-				}
-			}
-			return nil
-		})
-	}
-	// single file
-	stats.FilesScanned++
-	cnt, err := processFile(root, words, dryRun)
-	if err != nil {
-		return err
-	}
-	if cnt > 0 {
-		stats.FilesWithMatches++
-		stats.TotalMatches += cnt
-		stats.OpsPerFile[root] = cnt
-	}
-	return nil
+// fileResult is what a single processFile call found and did.
+type fileResult struct {
+	MatchedLines     int
+	AlreadyCommented bool
 }
 
-func processFile(path string, words []string, dryRun bool) (int, error) {
+func processFile(path string, sc *scanContext) (fileResult, error) {
 	f, err := os.Open(path)
 	if err != nil {
-		return 0, err
+		return fileResult{}, err
 	}
 	defer func() { _ = f.Close() }()
 
+	contentReader, codec, level, err := decompressedReader(f)
+	if err != nil {
+		return fileResult{}, err
+	}
+
+	if sc.Opts.YAMLAware {
+		return processFileYAMLAware(path, contentReader, codec, level, sc)
+	}
+
+	matcher, dryRun, rec := sc.Matcher, sc.DryRun, sc.Rec
 	var outLines []string
-	r := bufio.NewReader(f)
+	r := bufio.NewReader(contentReader)
 	lineNo := 0
 	modified := false
 	matchedLines := 0
+	preCommented := 0
 	for {
 		line, err := r.ReadString('\n')
 		if err != nil && err != io.EOF {
-			return 0, err
+			return fileResult{}, err
 		}
 		// handle last line without newline
 		rawLine := line
@@ -182,6 +288,7 @@ func processFile(path string, words []string, dryRun bool) (int, error) {
 
 		// skip already commented lines only when the very first character is '#'
 		if strings.HasPrefix(content, "#") {
+			preCommented++
 			outLines = append(outLines, rawLine)
 			if err == io.EOF {
 				break
@@ -189,22 +296,19 @@ func processFile(path string, words []string, dryRun bool) (int, error) {
 			continue
 		}
 
-		var matched []string
-		for _, w := range words {
-			if w == "" {
-				continue
-			}
-			if strings.Contains(content, w) {
-				matched = append(matched, w)
-			}
-		}
+		matched := matcher.Match(content)
 		if len(matched) > 0 {
 			modified = true
 			matchedLines++
-			fmt.Printf("%s:%d -> %s\n", path, lineNo, strings.Join(matched, ", "))
+			sc.Printer.Printf("%s:%d -> %s\n", path, lineNo, strings.Join(matched, ", "))
 			if dryRun {
 				outLines = append(outLines, rawLine)
 			} else {
+				if rec != nil {
+					if err := rec.record(path, lineNo, content, matched); err != nil {
+						return fileResult{}, err
+					}
+				}
 				// add comment marker at line start
 				newLine := "# " + content
 				if hasNL {
@@ -222,51 +326,9 @@ func processFile(path string, words []string, dryRun bool) (int, error) {
 	}
 
 	if modified && !dryRun {
-		// write back to a temp file then replace the original.
-		tmp := path + ".tmp_iceminus"
-		data := []byte(strings.Join(outLines, ""))
-		if err = os.WriteFile(tmp, data, 0644); err != nil {
-			return 0, err
-		}
-		// Try atomic rename first.
-		if err = os.Rename(tmp, path); err == nil {
-			return matchedLines, nil
-		}
-		// On Windows, rename may fail if the destination is locked or read-only.
-		// Try to remove destination then rename.
-		if remErr := os.Remove(path); remErr == nil {
-			if err = os.Rename(tmp, path); err == nil {
-				return matchedLines, nil
-			}
-		} else {
-			// attempt to make file writable and remove again
-			_ = os.Chmod(path, 0644)
-			if remErr2 := os.Remove(path); remErr2 == nil {
-				if err = os.Rename(tmp, path); err == nil {
-					return matchedLines, nil
-				}
-			}
-		}
-		// Fallback: overwrite the original file contents.
-		tmpData, readErr := os.ReadFile(tmp)
-		if readErr != nil {
-			return 0, fmt.Errorf("rename failed: %v; remove failed: %v; read tmp failed: %v", err, os.Remove(path), readErr)
-		}
-		of, openErr := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-		if openErr != nil {
-			// try to change permissions then open again
-			_ = os.Chmod(path, 0644)
-			of, openErr = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-			if openErr != nil {
-				return 0, fmt.Errorf("failed to overwrite file after rename failure: %v; open error: %v", err, openErr)
-			}
-		}
-		if _, writeErr := of.Write(tmpData); writeErr != nil {
-			_ = of.Close()
-			return 0, writeErr
+		if err := writeCommented(path, codec, level, outLines); err != nil {
+			return fileResult{}, err
 		}
-		_ = of.Close()
-		_ = os.Remove(tmp)
 	}
-	return matchedLines, nil
+	return fileResult{MatchedLines: matchedLines, AlreadyCommented: preCommented > 0}, nil
 }
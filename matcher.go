@@ -0,0 +1,163 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// dedupWords removes duplicates from matched, keeping the first occurrence
+// of each word. Used when a match is run against more than one TSV column
+// and the same word turns up in both.
+func dedupWords(matched []string) []string {
+	if len(matched) < 2 {
+		return matched
+	}
+	seen := make(map[string]bool, len(matched))
+	out := matched[:0]
+	for _, w := range matched {
+		if seen[w] {
+			continue
+		}
+		seen[w] = true
+		out = append(out, w)
+	}
+	return out
+}
+
+// Matcher finds every sensitive word occurring in a line of text.
+type Matcher interface {
+	// Match returns the sensitive words found in content, deduplicated and
+	// in the order the words were originally supplied.
+	Match(content string) []string
+}
+
+// naiveMatcher reproduces the original O(words) strings.Contains scan per
+// line. Kept around behind --matcher=naive as a fallback / correctness
+// baseline for the Aho-Corasick implementation.
+type naiveMatcher struct {
+	words []string
+}
+
+func newNaiveMatcher(words []string) *naiveMatcher {
+	return &naiveMatcher{words: words}
+}
+
+func (m *naiveMatcher) Match(content string) []string {
+	var matched []string
+	for _, w := range m.words {
+		if w == "" {
+			continue
+		}
+		if strings.Contains(content, w) {
+			matched = append(matched, w)
+		}
+	}
+	return matched
+}
+
+// acNode is a single state in the Aho-Corasick trie/automaton.
+type acNode struct {
+	children map[rune]*acNode
+	fail     *acNode
+	// output holds the indices of the words that terminate at this node, plus
+	// every word reachable by following failure links. The indices are not
+	// kept in word order; Match sorts what it collects before returning.
+	output []int
+}
+
+func newACNode() *acNode {
+	return &acNode{children: make(map[rune]*acNode)}
+}
+
+// ahoCorasick is a multi-pattern matcher built once from the sensitive word
+// list and shared (read-only) across every processFile call.
+type ahoCorasick struct {
+	root  *acNode
+	words []string
+}
+
+// newAhoCorasick builds the trie, wires up failure links via BFS and
+// precomputes each node's output set. words is kept so Match can report the
+// original strings rather than rebuilding them from runes.
+func newAhoCorasick(words []string) *ahoCorasick {
+	ac := &ahoCorasick{root: newACNode(), words: words}
+	for i, w := range words {
+		if w == "" {
+			continue
+		}
+		node := ac.root
+		for _, r := range w {
+			child, ok := node.children[r]
+			if !ok {
+				child = newACNode()
+				node.children[r] = child
+			}
+			node = child
+		}
+		node.output = append(node.output, i)
+	}
+	ac.buildFailureLinks()
+	return ac
+}
+
+func (ac *ahoCorasick) buildFailureLinks() {
+	queue := make([]*acNode, 0, len(ac.root.children))
+	for _, child := range ac.root.children {
+		child.fail = ac.root
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for r, child := range node.children {
+			fail := node.fail
+			for fail != nil {
+				if next, ok := fail.children[r]; ok {
+					child.fail = next
+					break
+				}
+				fail = fail.fail
+			}
+			if child.fail == nil {
+				child.fail = ac.root
+			}
+			child.output = append(child.output, child.fail.output...)
+			queue = append(queue, child)
+		}
+	}
+}
+
+// Match walks content once, following failure links whenever the current
+// state has no edge for the next rune, and collects the output set of every
+// state visited. Results are deduplicated but keep the original word order.
+func (ac *ahoCorasick) Match(content string) []string {
+	node := ac.root
+	seen := make(map[int]bool)
+	var hits []int
+	for _, r := range content {
+		for node != ac.root {
+			if _, ok := node.children[r]; ok {
+				break
+			}
+			node = node.fail
+		}
+		if next, ok := node.children[r]; ok {
+			node = next
+		}
+		for _, idx := range node.output {
+			if !seen[idx] {
+				seen[idx] = true
+				hits = append(hits, idx)
+			}
+		}
+	}
+	if len(hits) == 0 {
+		return nil
+	}
+	sort.Ints(hits)
+	matched := make([]string, len(hits))
+	for i, idx := range hits {
+		matched[i] = ac.words[idx]
+	}
+	return matched
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAhoCorasickMatchesNaive(t *testing.T) {
+	words := []string{"foo", "bar", "foobar", "baz"}
+	naive := newNaiveMatcher(words)
+	ac := newAhoCorasick(words)
+
+	lines := []string{
+		"nothing interesting here",
+		"a foo walked into a bar",
+		"foobar is both foo and bar",
+		"BAZ is not baz",
+	}
+	for _, line := range lines {
+		want := naive.Match(line)
+		got := ac.Match(line)
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Match(%q) = %v, want %v", line, got, want)
+		}
+	}
+}
+
+// benchDict builds a synthetic 500k-line dict and a word list sized the way
+// a real Rime cn_dicts sensitive-word scan would be, so the benchmark below
+// reflects the workload the naive strings.Contains loop struggled with.
+func benchDict(lines, words int) ([]string, []string) {
+	dictLines := make([]string, lines)
+	for i := range dictLines {
+		dictLines[i] = fmt.Sprintf("word%d\tcode%d\t%d", i, i, i%100)
+	}
+	wordList := make([]string, words)
+	for i := range wordList {
+		wordList[i] = fmt.Sprintf("sensitive%d", i)
+	}
+	// seed one real hit so the automaton does comparable work to a dict that
+	// actually contains sensitive content.
+	dictLines[lines/2] = "this line contains sensitive3 in the middle"
+	return dictLines, wordList
+}
+
+func BenchmarkNaiveMatcher(b *testing.B) {
+	lines, words := benchDict(500000, 2000)
+	m := newNaiveMatcher(words)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, line := range lines {
+			m.Match(line)
+		}
+	}
+}
+
+func BenchmarkAhoCorasick(b *testing.B) {
+	lines, words := benchDict(500000, 2000)
+	m := newAhoCorasick(words)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, line := range lines {
+			m.Match(line)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import "fmt"
+
+// printer serializes the "path:line -> words" diagnostic lines produced by
+// concurrent workers through a single goroutine, so stdout never
+// interleaves two lines that raced to print at the same time.
+type printer struct {
+	lines chan string
+	done  chan struct{}
+}
+
+func newPrinter() *printer {
+	p := &printer{lines: make(chan string, 256), done: make(chan struct{})}
+	go func() {
+		defer close(p.done)
+		for line := range p.lines {
+			fmt.Print(line)
+		}
+	}()
+	return p
+}
+
+// Printf formats and enqueues a line for the printer goroutine. It blocks
+// only when the 256-line buffer is full.
+func (p *printer) Printf(format string, args ...interface{}) {
+	p.lines <- fmt.Sprintf(format, args...)
+}
+
+// Close drains and stops the printer goroutine. Callers must not call
+// Printf after Close returns.
+func (p *printer) Close() {
+	close(p.lines)
+	<-p.done
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runUndo replays the journal in reverse for a single run, stripping the
+// leading "# " iceminus added and only when the remainder still exactly
+// matches what was journaled, so a file hand-edited after the run is left
+// alone. runID of "" picks the most recent run recorded in the journal.
+func runUndo(folderPath, runID string) error {
+	jp := journalPath(folderPath)
+	entries, err := loadJournal(jp)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(os.Stderr, "no journal entries found; nothing to undo")
+		return nil
+	}
+
+	if runID == "" {
+		runID = entries[len(entries)-1].RunID
+	}
+
+	var kept []JournalEntry
+	undone := 0
+	byFile := make(map[string][]JournalEntry)
+	for _, e := range entries {
+		if e.RunID != runID {
+			kept = append(kept, e)
+			continue
+		}
+		byFile[e.File] = append(byFile[e.File], e)
+	}
+	for file, fileEntries := range byFile {
+		reverted, err := undoFile(file, fileEntries)
+		if err != nil {
+			return fmt.Errorf("undo %s: %w", file, err)
+		}
+		for _, e := range fileEntries {
+			if reverted[e.Line] {
+				undone++
+			} else {
+				kept = append(kept, e)
+			}
+		}
+	}
+
+	if err := saveJournal(jp, kept); err != nil {
+		return err
+	}
+	fmt.Printf("undone %d operation(s) from run %s\n", undone, runID)
+	return nil
+}
+
+// undoFile strips the "# " iceminus added for each journaled line in file,
+// skipping (and reporting on stderr) any line whose current content no
+// longer matches what was recorded. It returns the set of line numbers that
+// were actually reverted, so the caller can keep a skipped entry's journal
+// record instead of erasing the only trace of an edit that never happened.
+func undoFile(file string, entries []JournalEntry) (map[int]bool, error) {
+	byLine := make(map[int]JournalEntry, len(entries))
+	for _, e := range entries {
+		byLine[e.Line] = e
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+
+	reverted := make(map[int]bool, len(entries))
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		lineNo := i + 1
+		entry, ok := byLine[lineNo]
+		if !ok {
+			continue
+		}
+		want := "# " + entry.OriginalText
+		if line != want {
+			fmt.Fprintf(os.Stderr, "%s:%d: current content no longer matches journal entry, skipping\n", file, lineNo)
+			continue
+		}
+		lines[i] = entry.OriginalText
+		reverted[lineNo] = true
+	}
+
+	if err := writeFileAtomic(file, []byte(strings.Join(lines, "\n")), info.Mode().Perm()); err != nil {
+		return nil, err
+	}
+	return reverted, nil
+}
+
+// runRestore replaces file with its pristine pre-iceminus backup.
+func runRestore(file string) error {
+	bp := backupPath(file)
+	data, err := os.ReadFile(bp)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no backup found at %s", bp)
+		}
+		return err
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(file, data, info.Mode().Perm()); err != nil {
+		return err
+	}
+	fmt.Printf("restored %s from %s\n", file, bp)
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunUndoKeepsJournalEntryOnMismatch(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "test.dict.yaml")
+	if err := os.WriteFile(file, []byte("# hand-edited\nworld\tshi jie\t50\n"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	jp := journalPath(dir)
+	entries := []JournalEntry{
+		{File: file, Line: 1, OriginalText: "hello\tni hao\t100", RunID: "run1"},
+	}
+	if err := saveJournal(jp, entries); err != nil {
+		t.Fatalf("saveJournal: %v", err)
+	}
+
+	if err := runUndo(dir, "run1"); err != nil {
+		t.Fatalf("runUndo: %v", err)
+	}
+
+	kept, err := loadJournal(jp)
+	if err != nil {
+		t.Fatalf("loadJournal: %v", err)
+	}
+	if len(kept) != 1 {
+		t.Fatalf("got %d journal entries after a mismatched undo, want 1 (the skipped entry should survive)", len(kept))
+	}
+	if kept[0].Line != 1 || kept[0].OriginalText != "hello\tni hao\t100" {
+		t.Errorf("kept entry = %+v, want the original untouched entry", kept[0])
+	}
+
+	got, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(got) != "# hand-edited\nworld\tshi jie\t50\n" {
+		t.Errorf("file content changed despite the mismatch: %q", got)
+	}
+}
+
+func TestRunUndoRevertsMatchingEntry(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "test.dict.yaml")
+	if err := os.WriteFile(file, []byte("# hello\tni hao\t100\nworld\tshi jie\t50\n"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	jp := journalPath(dir)
+	entries := []JournalEntry{
+		{File: file, Line: 1, OriginalText: "hello\tni hao\t100", RunID: "run1"},
+	}
+	if err := saveJournal(jp, entries); err != nil {
+		t.Fatalf("saveJournal: %v", err)
+	}
+
+	if err := runUndo(dir, "run1"); err != nil {
+		t.Fatalf("runUndo: %v", err)
+	}
+
+	kept, err := loadJournal(jp)
+	if err != nil {
+		t.Fatalf("loadJournal: %v", err)
+	}
+	if len(kept) != 0 {
+		t.Fatalf("got %d journal entries after a successful undo, want 0", len(kept))
+	}
+
+	got, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(got) != "hello\tni hao\t100\nworld\tshi jie\t50\n" {
+		t.Errorf("file = %q, want the comment stripped", got)
+	}
+}
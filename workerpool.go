@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// scanContext bundles everything a file scan needs that's shared read-only
+// (or internally synchronized) across every worker in the pool: the
+// Aho-Corasick/naive matcher, the run's journal recorder, the incremental
+// cache, and the scan mode. None of it is worker-local, unlike procStats.
+type scanContext struct {
+	Matcher   Matcher
+	DryRun    bool
+	Rec       *runRecorder
+	Cache     *fileCache
+	WordsHash string
+	OptsHash  string
+	Opts      scanOptions
+	Printer   *printer
+}
+
+// processPath walks root and scans every dict file it finds using a bounded
+// pool of jobs workers. filepath.WalkDir stays on a single goroutine and
+// only pushes candidate paths into a buffered channel; the actual scanning
+// (the expensive part) happens on the workers. The first error from any
+// worker or from the walk cancels ctx so the rest of the pipeline shuts down
+// instead of grinding through the remaining files.
+func processPath(root string, sc *scanContext, stats *procStats, jobs int) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if jobs < 1 {
+		jobs = runtime.NumCPU()
+	}
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	if !info.IsDir() {
+		// A single file isn't worth spinning up a pool for.
+		local := &procStats{OpsPerFile: make(map[string]int)}
+		local.FilesScanned++
+		if err := processOne(root, sc, local); err != nil {
+			return err
+		}
+		mergeStats(stats, local)
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	paths := make(chan string, jobs*4)
+	var mu sync.Mutex
+	var firstErr error
+	recordErr := func(err error) {
+		if err == nil {
+			return
+		}
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+		mu.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			local := &procStats{OpsPerFile: make(map[string]int)}
+			for {
+				select {
+				case <-ctx.Done():
+					mergeStats(stats, local)
+					return
+				case path, ok := <-paths:
+					if !ok {
+						mergeStats(stats, local)
+						return
+					}
+					local.FilesScanned++
+					if err := processOne(path, sc, local); err != nil {
+						recordErr(err)
+					}
+				}
+			}
+		}()
+	}
+
+	walkErr := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if !isDictFile(path) {
+			return nil
+		}
+		select {
+		case paths <- path:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	if walkErr != nil && walkErr != context.Canceled {
+		cancel()
+	}
+	close(paths)
+	wg.Wait()
+
+	if walkErr != nil && walkErr != context.Canceled {
+		return walkErr
+	}
+	return firstErr
+}
+
+// mergeStats folds a worker's local counts into the shared totals. It's
+// only ever called by the owner of local (a worker, or processPath itself for
+// a single file) once it is done with it, so the shared stats only need to be
+// protected against the other workers doing the same thing at the same time.
+var statsMu sync.Mutex
+
+func mergeStats(dst, src *procStats) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	dst.FilesScanned += src.FilesScanned
+	dst.FilesCached += src.FilesCached
+	dst.FilesWithMatches += src.FilesWithMatches
+	dst.TotalMatches += src.TotalMatches
+	for path, cnt := range src.OpsPerFile {
+		dst.OpsPerFile[path] = cnt
+	}
+}
+
+// processOne runs the cache lookup (if enabled) and falls back to
+// processFile, updating stats either way.
+func processOne(path string, sc *scanContext, stats *procStats) error {
+	var fileHash, cacheKey string
+	if sc.Cache != nil {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		cacheKey = abs
+		fileHash, err = hashFile(path)
+		if err != nil {
+			return err
+		}
+		if _, ok := sc.Cache.lookup(cacheKey, fileHash, sc.WordsHash, sc.OptsHash); ok {
+			stats.FilesCached++
+			return nil
+		}
+	}
+
+	result, err := processFile(path, sc)
+	if err != nil {
+		return err
+	}
+	if result.MatchedLines > 0 {
+		stats.FilesWithMatches++
+		stats.TotalMatches += result.MatchedLines
+		stats.OpsPerFile[path] = result.MatchedLines
+	}
+
+	if sc.Cache != nil && !sc.DryRun && result.MatchedLines == 0 {
+		outcome := cacheResultClean
+		if result.AlreadyCommented {
+			outcome = cacheResultComplete
+		}
+		if err := sc.Cache.record(cacheKey, fileHash, sc.WordsHash, sc.OptsHash, outcome); err != nil {
+			return err
+		}
+	}
+	return nil
+}
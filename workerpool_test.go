@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFixture populates dir with n .yaml files, every fifth one containing a
+// sensitive word, so the pool has a predictable number of hits to find
+// regardless of how many workers race through them.
+func writeFixture(t *testing.T, dir string, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		content := fmt.Sprintf("entry%d\tcode%d\t1\n", i, i)
+		if i%5 == 0 {
+			content += "badword\tcode\t1\n"
+		}
+		name := filepath.Join(dir, fmt.Sprintf("dict%03d.yaml", i))
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatalf("write fixture file: %v", err)
+		}
+	}
+}
+
+// TestProcessPathDeterministicAcrossJobs runs the same 200-file fixture
+// through the worker pool with several --jobs values and asserts the
+// resulting stats are identical, since scheduling order must not be allowed
+// to change what gets counted.
+func TestProcessPathDeterministicAcrossJobs(t *testing.T) {
+	const fileCount = 200
+	matcher := newAhoCorasick([]string{"badword"})
+
+	var want *procStats
+	for _, jobs := range []int{1, 4, 16} {
+		dir := t.TempDir()
+		writeFixture(t, dir, fileCount)
+
+		sc := &scanContext{
+			Matcher: matcher,
+			DryRun:  true,
+			Opts:    scanOptions{YAMLAware: false},
+			Printer: newPrinter(),
+		}
+		stats := &procStats{OpsPerFile: make(map[string]int)}
+		if err := processPath(dir, sc, stats, jobs); err != nil {
+			t.Fatalf("processPath(jobs=%d): %v", jobs, err)
+		}
+		sc.Printer.Close()
+
+		if want == nil {
+			want = stats
+			continue
+		}
+		if stats.FilesScanned != want.FilesScanned ||
+			stats.FilesWithMatches != want.FilesWithMatches ||
+			stats.TotalMatches != want.TotalMatches ||
+			len(stats.OpsPerFile) != len(want.OpsPerFile) {
+			t.Fatalf("jobs=%d produced %+v, want %+v", jobs, stats, want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/uselibrary/iceminus/internal/compress"
+	"github.com/uselibrary/iceminus/internal/rimedict"
+)
+
+// scanOptions controls how processFile interprets a dict file's structure.
+type scanOptions struct {
+	// YAMLAware enables the structured Rime-dict scan (header vs TSV body)
+	// instead of matching whole raw lines. Disabled by --legacy-lines.
+	YAMLAware bool
+	// Strict makes a sensitive-word match in the YAML header a hard error
+	// instead of a warning.
+	Strict bool
+	// MatchColumns lists which TSV columns of a body entry to match
+	// against: "text", "code", or both.
+	MatchColumns []string
+}
+
+// parseYAMLAwareFlag turns the --yaml-aware flag value into (enabled, strict).
+func parseYAMLAwareFlag(value string) (enabled, strict bool, err error) {
+	switch strings.ToLower(value) {
+	case "on", "true", "1", "":
+		return true, false, nil
+	case "off", "false", "0":
+		return false, false, nil
+	case "strict":
+		return true, true, nil
+	default:
+		return false, false, fmt.Errorf("unknown --yaml-aware %q; want on, off, or strict", value)
+	}
+}
+
+func parseMatchColumns(value string) ([]string, error) {
+	var columns []string
+	for _, c := range strings.Split(value, ",") {
+		c = strings.TrimSpace(strings.ToLower(c))
+		if c == "" {
+			continue
+		}
+		if c != "text" && c != "code" {
+			return nil, fmt.Errorf("unknown --match-columns entry %q; want text or code", c)
+		}
+		columns = append(columns, c)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("--match-columns must name at least one column")
+	}
+	return columns, nil
+}
+
+// processFileYAMLAware is the structured driver on top of rimedict.Scanner:
+// only TSV body entries are eligible for commenting, and a match inside the
+// YAML header is reported rather than rewritten so a "description:" field
+// that happens to contain a sensitive substring can't corrupt the dict. It
+// takes the shared scanContext rather than individual fields so it can run
+// unmodified on a worker-pool goroutine.
+func processFileYAMLAware(path string, contentReader io.Reader, codec compress.Codec, level int, sc *scanContext) (fileResult, error) {
+	matcher, dryRun, rec, opts := sc.Matcher, sc.DryRun, sc.Rec, sc.Opts
+	scanner := rimedict.NewScanner(contentReader)
+
+	var outLines []string
+	modified := false
+	matchedLines := 0
+	preCommented := 0
+
+	for scanner.Scan() {
+		line := scanner.Line()
+		switch line.Kind {
+		case rimedict.KindBlank, rimedict.KindComment:
+			if line.Kind == rimedict.KindComment {
+				preCommented++
+			}
+			outLines = append(outLines, line.Raw)
+
+		case rimedict.KindHeader:
+			content := strings.TrimSuffix(line.Raw, "\n")
+			if matched := matcher.Match(content); len(matched) > 0 {
+				msg := fmt.Sprintf("%s:%d: sensitive word(s) %s found in dict header (not rewritten)", path, line.LineNo, strings.Join(matched, ", "))
+				if opts.Strict {
+					return fileResult{}, fmt.Errorf("%s; failing due to --yaml-aware=strict", msg)
+				}
+				fmt.Fprintln(os.Stderr, "warning: "+msg)
+			}
+			outLines = append(outLines, line.Raw)
+
+		case rimedict.KindBody:
+			var matched []string
+			for _, col := range opts.MatchColumns {
+				switch col {
+				case "text":
+					matched = append(matched, matcher.Match(line.Entry.Text)...)
+				case "code":
+					matched = append(matched, matcher.Match(line.Entry.Code)...)
+				}
+			}
+			matched = dedupWords(matched)
+			if len(matched) == 0 {
+				outLines = append(outLines, line.Raw)
+				continue
+			}
+
+			modified = true
+			matchedLines++
+			sc.Printer.Printf("%s:%d -> %s\n", path, line.LineNo, strings.Join(matched, ", "))
+			if dryRun {
+				outLines = append(outLines, line.Raw)
+				continue
+			}
+			content := strings.TrimSuffix(line.Raw, "\n")
+			if rec != nil {
+				if err := rec.record(path, line.LineNo, content, matched); err != nil {
+					return fileResult{}, err
+				}
+			}
+			newLine := "# " + content
+			if strings.HasSuffix(line.Raw, "\n") {
+				newLine += "\n"
+			}
+			outLines = append(outLines, newLine)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fileResult{}, err
+	}
+
+	if modified && !dryRun {
+		if err := writeCommented(path, codec, level, outLines); err != nil {
+			return fileResult{}, err
+		}
+	}
+	return fileResult{MatchedLines: matchedLines, AlreadyCommented: preCommented > 0}, nil
+}